@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Dialect abstracts the SQL and bulk-load syntax differences between the
+// database engines csvimport can target, selected with --dialect. handle
+// and the alltables.sql / --merge composition in main.go all go through
+// this interface instead of hard-coding postgres syntax.
+type Dialect interface {
+	// Name is the --dialect flag value that selects this implementation.
+	Name() string
+	// QuoteIdent quotes name as an identifier for this engine.
+	QuoteIdent(name string) string
+	// MapType returns the column type for f, including a NOT NULL clause
+	// when no row for that column was empty. A plain text column (no
+	// --schema type override) never gets the NOT NULL clause.
+	MapType(f *fieldType) string
+	// CreateTable writes the (optional drop plus) create table statement
+	// for tablename to out.
+	CreateTable(out io.Writer, tablename string, rawNames, columnNames []string, types []*fieldType) error
+	// BulkLoadPrologue writes whatever statement(s) are needed to start
+	// the bulk load for tablename to out, and returns the RowWriter rows
+	// should be streamed to: one backed by out itself for engines that
+	// accept inline data (postgres), or by a sidecar file for engines
+	// that load from a separate path (mysql, sqlite, mssql).
+	BulkLoadPrologue(out io.Writer, basename, tablename string, columnNames []string) (RowWriter, error)
+	// BulkLoadEpilogue closes rw and writes whatever trailing statement
+	// completes the bulk load (and commits the transaction CreateTable
+	// opened, for dialects whose CreateTable leaves it open) to out.
+	BulkLoadEpilogue(out io.Writer, rw RowWriter) error
+	// Merge writes the statement that gathers selects (each already
+	// rendered by buildMergeSelect, in the same column order as
+	// columnNames) into merge, as a view, a CREATE TABLE AS, or an INSERT
+	// INTO an existing table, per mode ("view", "table" or "insert").
+	Merge(out io.Writer, merge, mode string, columnNames []string, selects []string) error
+	// IncludeFile writes whatever statement this engine's own CLI uses to
+	// run another script file, for alltables.sql to pull in each file's
+	// generated .sql: psql's \i, mysql's source, sqlite3's .import-style
+	// .read, or sqlcmd's :r.
+	IncludeFile(out io.Writer, path string) error
+}
+
+// RowWriter accepts the already-translated fields of one CSV row, in
+// column order, and is how translateRows stays agnostic of whether it's
+// writing inline csv or to a dialect's sidecar file.
+type RowWriter interface {
+	WriteRow(fields []string) error
+	Close() error
+}
+
+// constraintStatements renders the PRIMARY KEY / CREATE UNIQUE INDEX /
+// CREATE INDEX statements a --schema hints file asked for on tablename's
+// columns. It's shared by every dialect's table DDL: naming indexes
+// ix_<table>_<column> / uq_<table>_<column> keeps the syntax identical
+// everywhere, only QuoteIdent varies. inlinePrimaryKey skips the ALTER
+// TABLE ADD PRIMARY KEY statement for dialects (sqlite) that can't add a
+// primary key after the fact and instead declare it inline in CREATE
+// TABLE; those dialects' tableDDL renders it itself.
+func constraintStatements(d Dialect, tablename string, columnNames []string, types []*fieldType, inlinePrimaryKey bool) []string {
+	var stmts []string
+	if !inlinePrimaryKey {
+		var primaryKey []string
+		for i, ft := range types {
+			if ft.PrimaryKey {
+				primaryKey = append(primaryKey, d.QuoteIdent(columnNames[i]))
+			}
+		}
+		if len(primaryKey) > 0 {
+			stmts = append(stmts, fmt.Sprintf("alter table %s add primary key (%s);\n", d.QuoteIdent(tablename), strings.Join(primaryKey, ", ")))
+		}
+	}
+	for i, ft := range types {
+		if ft.Unique {
+			stmts = append(stmts, fmt.Sprintf("create unique index %s on %s (%s);\n",
+				d.QuoteIdent(fmt.Sprintf("uq_%s_%s", tablename, columnNames[i])), d.QuoteIdent(tablename), d.QuoteIdent(columnNames[i])))
+		}
+		if ft.Index {
+			stmts = append(stmts, fmt.Sprintf("create index %s on %s (%s);\n",
+				d.QuoteIdent(fmt.Sprintf("ix_%s_%s", tablename, columnNames[i])), d.QuoteIdent(tablename), d.QuoteIdent(columnNames[i])))
+		}
+	}
+	return stmts
+}
+
+var dialectRegistry = map[string]func() Dialect{
+	"postgres": func() Dialect { return &postgresDialect{} },
+	"mysql":    func() Dialect { return &mysqlDialect{} },
+	"sqlite":   func() Dialect { return &sqliteDialect{} },
+	"mssql":    func() Dialect { return &mssqlDialect{} },
+}
+
+func pickDialect(name string) (Dialect, error) {
+	ctor, ok := dialectRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown --dialect %q", name)
+	}
+	return ctor(), nil
+}
+
+// csvRowWriter is a RowWriter backed directly by the writer handed to
+// BulkLoadPrologue: used by dialects whose bulk-load statement reads data
+// inline rather than from a separate file.
+type csvRowWriter struct {
+	cw *csv.Writer
+}
+
+func (w *csvRowWriter) WriteRow(fields []string) error { return w.cw.Write(fields) }
+
+func (w *csvRowWriter) Close() error {
+	w.cw.Flush()
+	return w.cw.Error()
+}
+
+// sidecarRowWriter is a RowWriter backed by a separate CSV file on disk,
+// for dialects whose bulk-load statement names a file to read from
+// (LOAD DATA INFILE, BULK INSERT, .import).
+type sidecarRowWriter struct {
+	path        string
+	tablename   string
+	columnNames []string
+	file        *os.File
+	cw          *csv.Writer
+}
+
+func createSidecar(basename, tablename string, columnNames []string) (*sidecarRowWriter, error) {
+	path := basename + ".data.csv"
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &sidecarRowWriter{path: path, tablename: tablename, columnNames: columnNames, file: f, cw: csv.NewWriter(f)}, nil
+}
+
+func (w *sidecarRowWriter) WriteRow(fields []string) error { return w.cw.Write(fields) }
+
+func (w *sidecarRowWriter) Close() error {
+	w.cw.Flush()
+	if err := w.cw.Error(); err != nil {
+		_ = w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
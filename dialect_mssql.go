@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// mssqlDialect targets SQL Server: bulk data goes through a sidecar CSV
+// file plus a non-XML bcp format file, loaded with BULK INSERT.
+type mssqlDialect struct{}
+
+func (mssqlDialect) Name() string { return "mssql" }
+
+func (mssqlDialect) QuoteIdent(name string) string { return "[" + name + "]" }
+
+func (mssqlDialect) MapType(f *fieldType) string {
+	mod := ""
+	if f.NotNull() {
+		mod = " not null"
+	}
+	if f.Override != "" {
+		return f.Override + mod
+	}
+	switch f.Kind() {
+	case kindDate:
+		if f.DateTime {
+			return "datetime2" + mod
+		}
+		return "date" + mod
+	case kindBool:
+		return "bit" + mod
+	case kindInt:
+		return "int" + mod
+	case kindFloat:
+		if precision, scale, ok := f.Precision(); ok {
+			return fmt.Sprintf("decimal(%d,%d)%s", precision, scale, mod)
+		}
+		return "float" + mod
+	case kindUUID:
+		return "uniqueidentifier" + mod
+	case kindJSON:
+		return "nvarchar(max)" + mod
+	default:
+		return "nvarchar(max)"
+	}
+}
+
+func (d mssqlDialect) CreateTable(out io.Writer, tablename string, rawNames, columnNames []string, types []*fieldType) error {
+	if _, err := fmt.Fprintf(out, "begin transaction;\n\n"); err != nil {
+		return err
+	}
+	if clean {
+		if _, err := fmt.Fprintf(out, "drop table if exists %s;\n", d.QuoteIdent(tablename)); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(out, "create table %s (\n", d.QuoteIdent(tablename)); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 4, 4, 1, ' ', 0)
+	for i, name := range rawNames {
+		comma := ","
+		if i == len(rawNames)-1 {
+			comma = ""
+		}
+		if _, err := w.Write([]byte(fmt.Sprintf("\t%s\t%s%s\t-- %s\n", d.QuoteIdent(columnNames[i]), d.MapType(types[i]), comma, name))); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, ");\n\n"); err != nil {
+		return err
+	}
+	for _, stmt := range constraintStatements(d, tablename, columnNames, types, false) {
+		if _, err := fmt.Fprintf(out, "%s\n", stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mssqlDialect) BulkLoadPrologue(out io.Writer, basename, tablename string, columnNames []string) (RowWriter, error) {
+	return createSidecar(basename, tablename, columnNames)
+}
+
+func (d mssqlDialect) BulkLoadEpilogue(out io.Writer, rw RowWriter) error {
+	sc, ok := rw.(*sidecarRowWriter)
+	if !ok {
+		return fmt.Errorf("mssql dialect requires a sidecar row writer")
+	}
+	if err := sc.Close(); err != nil {
+		return err
+	}
+	fmtPath := sc.path + ".fmt"
+	if err := writeBCPFormatFile(fmtPath, sc.columnNames); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, "bulk insert %s from '%s' with (formatfile = '%s');\n\n",
+		d.QuoteIdent(sc.tablename), sc.path, fmtPath); err != nil {
+		return err
+	}
+	// BULK INSERT has no option to declare a NULL marker string the way
+	// postgres COPY's "null '...'" does, so --null-string's literal text
+	// lands in every matching column as-is; turn it into real NULLs here.
+	if nullString != "" {
+		for _, col := range sc.columnNames {
+			ident := d.QuoteIdent(col)
+			if _, err := fmt.Fprintf(out, "update %s set %s = null where %s = '%s';\n",
+				d.QuoteIdent(sc.tablename), ident, ident, escapeSQLString(nullString)); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(out, "\n"); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(out, "commit transaction;\n\n")
+	return err
+}
+
+// IncludeFile uses sqlcmd's :r scripting variable, its equivalent of
+// psql's \i; sqlcmd must be invoked with -x (or have SQLCMD mode enabled)
+// for script commands to be recognized.
+func (mssqlDialect) IncludeFile(out io.Writer, path string) error {
+	_, err := fmt.Fprintf(out, ":r %s\n", path)
+	return err
+}
+
+// Merge's "table" mode uses select ... into, since T-SQL has no create
+// table as select.
+func (d mssqlDialect) Merge(out io.Writer, merge, mode string, columnNames []string, selects []string) error {
+	union := strings.Join(selects, "\nunion all\n")
+	switch mode {
+	case "view":
+		if _, err := fmt.Fprintf(out, "drop view if exists %s;\n", d.QuoteIdent(merge)); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(out, "create view %s as\n%s;\n\n", d.QuoteIdent(merge), union)
+		return err
+	case "insert":
+		_, err := fmt.Fprintf(out, "insert into %s (%s)\n%s;\n\n", d.QuoteIdent(merge), strings.Join(columnNames, ", "), union)
+		return err
+	default:
+		if clean {
+			if _, err := fmt.Fprintf(out, "drop table if exists %s;\n", d.QuoteIdent(merge)); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(out, "select * into %s from (\n%s\n) as merged;\n\n", d.QuoteIdent(merge), union)
+		return err
+	}
+}
+
+// writeBCPFormatFile writes a minimal non-XML bcp format file alongside a
+// mssql sidecar, describing each of columnNames as a comma-separated
+// SQLCHAR field, which is all BULK INSERT needs to parse the sidecar csv.
+func writeBCPFormatFile(path string, columnNames []string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, _ = fmt.Fprintf(f, "9.0\n%d\n", len(columnNames))
+	for i, name := range columnNames {
+		terminator := `","`
+		if i == len(columnNames)-1 {
+			terminator = `"\r\n"`
+		}
+		_, _ = fmt.Fprintf(f, "%d       SQLCHAR       0       0       %s       %d       %s       \"\"\n",
+			i+1, terminator, i+1, name)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlDialect targets MySQL/MariaDB: bulk data goes through a sidecar CSV
+// file loaded with LOAD DATA LOCAL INFILE rather than inline.
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) QuoteIdent(name string) string { return "`" + name + "`" }
+
+func (mysqlDialect) MapType(f *fieldType) string {
+	mod := ""
+	if f.NotNull() {
+		mod = " not null"
+	}
+	if f.Override != "" {
+		return f.Override + mod
+	}
+	switch f.Kind() {
+	case kindDate:
+		if f.DateTime {
+			return "datetime" + mod
+		}
+		return "date" + mod
+	case kindBool:
+		return "boolean" + mod
+	case kindInt:
+		return "integer" + mod
+	case kindFloat:
+		if precision, scale, ok := f.Precision(); ok {
+			return fmt.Sprintf("decimal(%d,%d)%s", precision, scale, mod)
+		}
+		return "double" + mod
+	case kindUUID:
+		return "char(36)" + mod
+	case kindJSON:
+		return "json" + mod
+	default:
+		return "text"
+	}
+}
+
+func (d mysqlDialect) CreateTable(out io.Writer, tablename string, rawNames, columnNames []string, types []*fieldType) error {
+	if _, err := fmt.Fprintf(out, "start transaction;\n\n"); err != nil {
+		return err
+	}
+	return d.tableDDL(out, tablename, rawNames, columnNames, types)
+}
+
+// tableDDL writes just the (optional drop plus) create table statement,
+// with no surrounding transaction; see postgresDialect.tableDDL.
+func (d mysqlDialect) tableDDL(out io.Writer, tablename string, rawNames, columnNames []string, types []*fieldType) error {
+	if clean {
+		if _, err := fmt.Fprintf(out, "drop table if exists %s;\n", d.QuoteIdent(tablename)); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(out, "create table %s (\n", d.QuoteIdent(tablename)); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 4, 4, 1, ' ', 0)
+	for i, name := range rawNames {
+		comma := ","
+		if i == len(rawNames)-1 {
+			comma = ""
+		}
+		if _, err := w.Write([]byte(fmt.Sprintf("\t%s\t%s%s\t-- %s\n", d.QuoteIdent(columnNames[i]), d.MapType(types[i]), comma, name))); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, ");\n\n"); err != nil {
+		return err
+	}
+	for _, stmt := range constraintStatements(d, tablename, columnNames, types, false) {
+		if _, err := fmt.Fprintf(out, "%s\n", stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mysqlDialect) BulkLoadPrologue(out io.Writer, basename, tablename string, columnNames []string) (RowWriter, error) {
+	return createSidecar(basename, tablename, columnNames)
+}
+
+func (d mysqlDialect) BulkLoadEpilogue(out io.Writer, rw RowWriter) error {
+	sc, ok := rw.(*sidecarRowWriter)
+	if !ok {
+		return fmt.Errorf("mysql dialect requires a sidecar row writer")
+	}
+	if err := sc.Close(); err != nil {
+		return err
+	}
+	// The sidecar is written by createSidecar's csv.Writer: comma-separated,
+	// with no header row.
+	_, err := fmt.Fprintf(out, "load data local infile '%s' into table %s\n"+
+		"fields terminated by ',' optionally enclosed by '%s'\n"+
+		"lines terminated by '\\n'\n"+
+		"%s;\n\ncommit;\n\n",
+		sc.path, d.QuoteIdent(sc.tablename), escapeSQLString(quote), nullClause(d, sc.columnNames))
+	return err
+}
+
+// nullClause returns the "(col1, col2, ...) SET col1 = NULLIF(col1, '...'),
+// ..." suffix that tells LOAD DATA to turn --null-string's marker text into
+// SQL NULL, since LOAD DATA has no direct equivalent of postgres COPY's
+// "null '...'" option. It's empty when --null-string wasn't set.
+func nullClause(d mysqlDialect, columnNames []string) string {
+	if nullString == "" {
+		return ""
+	}
+	quoted := make([]string, len(columnNames))
+	sets := make([]string, len(columnNames))
+	for i, name := range columnNames {
+		ident := d.QuoteIdent(name)
+		quoted[i] = ident
+		sets[i] = fmt.Sprintf("%s = nullif(%s, '%s')", ident, ident, escapeSQLString(nullString))
+	}
+	return fmt.Sprintf("(%s)\nset %s", strings.Join(quoted, ", "), strings.Join(sets, ", "))
+}
+
+// IncludeFile uses mysql's source command, the mysql CLI's equivalent of
+// psql's \i.
+func (mysqlDialect) IncludeFile(out io.Writer, path string) error {
+	_, err := fmt.Fprintf(out, "source %s;\n", path)
+	return err
+}
+
+func (d mysqlDialect) Merge(out io.Writer, merge, mode string, columnNames []string, selects []string) error {
+	union := strings.Join(selects, "\nunion all\n")
+	switch mode {
+	case "view":
+		_, err := fmt.Fprintf(out, "create or replace view %s as\n%s;\n\n", d.QuoteIdent(merge), union)
+		return err
+	case "insert":
+		_, err := fmt.Fprintf(out, "insert into %s (%s)\n%s;\n\n", d.QuoteIdent(merge), strings.Join(columnNames, ", "), union)
+		return err
+	default:
+		if clean {
+			if _, err := fmt.Fprintf(out, "drop table if exists %s;\n", d.QuoteIdent(merge)); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(out, "create table %s as\n%s;\n\n", d.QuoteIdent(merge), union)
+		return err
+	}
+}
+
+// OpenDB opens dsn with the go-sql-driver/mysql driver.
+func (mysqlDialect) OpenDB(dsn string) (*sql.DB, error) {
+	return sql.Open("mysql", dsn)
+}
+
+// LoadFile loads filename into tablename over a live connection. It still
+// goes via a sidecar CSV file and LOAD DATA LOCAL INFILE, the same as the
+// generated .sql path, since that remains MySQL's fastest bulk load -- the
+// sidecar is just registered with the driver instead of left for the user
+// to point the mysql client at.
+func (d mysqlDialect) LoadFile(ctx context.Context, db *sql.DB, filename, tablename string, rawNames, columnNames []string, types []*fieldType) (int64, []int, error) {
+	sc, err := createSidecar(strings.TrimSuffix(filename, ".csv"), tablename, columnNames)
+	if err != nil {
+		return 0, nil, err
+	}
+	rowsLoaded, errorRows, err := translateRowsCounted(filename, types, sc)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	mysql.RegisterLocalFile(sc.path)
+	defer mysql.DeregisterLocalFile(sc.path)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if clean {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("drop table if exists %s", d.QuoteIdent(tablename))); err != nil {
+			return 0, nil, err
+		}
+	}
+	var ddl bytes.Buffer
+	if err := d.tableDDL(&ddl, tablename, rawNames, columnNames, types); err != nil {
+		return 0, nil, err
+	}
+	if _, err := tx.ExecContext(ctx, ddl.String()); err != nil {
+		return 0, nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(
+		"load data local infile '%s' into table %s "+
+			"fields terminated by ',' optionally enclosed by '%s' "+
+			"lines terminated by '\\n' %s",
+		sc.path, d.QuoteIdent(tablename), escapeSQLString(quote), nullClause(d, columnNames)))
+	if err != nil {
+		return 0, nil, err
+	}
+	return rowsLoaded, errorRows, tx.Commit()
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresDialect is the original, default target: it writes plain SQL
+// files meant to be fed to psql, using copy ... from stdin for bulk data.
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) QuoteIdent(name string) string { return name }
+
+func (postgresDialect) MapType(f *fieldType) string {
+	mod := ""
+	if f.NotNull() {
+		mod = " not null"
+	}
+	if f.Override != "" {
+		return f.Override + mod
+	}
+	switch f.Kind() {
+	case kindDate:
+		if f.DateTime {
+			return "timestamptz" + mod
+		}
+		return "date" + mod
+	case kindBool:
+		return "boolean" + mod
+	case kindInt:
+		return "integer" + mod
+	case kindFloat:
+		if precision, scale, ok := f.Precision(); ok {
+			return fmt.Sprintf("numeric(%d,%d)%s", precision, scale, mod)
+		}
+		return "float" + mod
+	case kindUUID:
+		return "uuid" + mod
+	case kindJSON:
+		return "jsonb" + mod
+	default:
+		return "text"
+	}
+}
+
+func (d postgresDialect) CreateTable(out io.Writer, tablename string, rawNames, columnNames []string, types []*fieldType) error {
+	if _, err := fmt.Fprintf(out, "begin;\n\n"); err != nil {
+		return err
+	}
+	return d.tableDDL(out, tablename, rawNames, columnNames, types)
+}
+
+// tableDDL writes just the (optional drop plus) create table statement,
+// with no surrounding transaction: handle's generated .sql files wrap it in
+// one via CreateTable, while a --dsn direct load manages its own
+// transaction around a live connection and calls this directly.
+func (d postgresDialect) tableDDL(out io.Writer, tablename string, rawNames, columnNames []string, types []*fieldType) error {
+	if clean {
+		if _, err := fmt.Fprintf(out, "drop table if exists %s;\n", tablename); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(out, "create table %s (\n", tablename); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 4, 4, 1, ' ', 0)
+	for i, name := range rawNames {
+		comma := ","
+		if i == len(rawNames)-1 {
+			comma = ""
+		}
+		if _, err := w.Write([]byte(fmt.Sprintf("\t%s\t%s%s\t-- %s\n", columnNames[i], d.MapType(types[i]), comma, name))); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, ");\n\n"); err != nil {
+		return err
+	}
+	for _, stmt := range constraintStatements(d, tablename, columnNames, types, false) {
+		if _, err := fmt.Fprintf(out, "%s\n", stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (postgresDialect) BulkLoadPrologue(out io.Writer, basename, tablename string, columnNames []string) (RowWriter, error) {
+	if _, err := fmt.Fprintf(out, "copy %s (%s) from stdin %s;\n", tablename, strings.Join(columnNames, ", "), copyOptions()); err != nil {
+		return nil, err
+	}
+	cw := csv.NewWriter(out)
+	if delimiter != "" {
+		cw.Comma = []rune(delimiter)[0]
+	}
+	return &csvRowWriter{cw: cw}, nil
+}
+
+func (postgresDialect) BulkLoadEpilogue(out io.Writer, rw RowWriter) error {
+	if err := rw.Close(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "\\.\n\ncommit;\n\n")
+	return err
+}
+
+func (postgresDialect) IncludeFile(out io.Writer, path string) error {
+	_, err := fmt.Fprintf(out, "\\i '%s'\n", path)
+	return err
+}
+
+func (postgresDialect) Merge(out io.Writer, merge, mode string, columnNames []string, selects []string) error {
+	union := strings.Join(selects, "\nunion all\n")
+	switch mode {
+	case "view":
+		_, err := fmt.Fprintf(out, "create or replace view %s as\n%s;\n\n", merge, union)
+		return err
+	case "insert":
+		_, err := fmt.Fprintf(out, "insert into %s (%s)\n%s;\n\n", merge, strings.Join(columnNames, ", "), union)
+		return err
+	default:
+		if clean {
+			if _, err := fmt.Fprintf(out, "drop table if exists %s;\n", merge); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(out, "create table %s as\n%s;\n\n", merge, union)
+		return err
+	}
+}
+
+// OpenDB opens dsn with the pgx stdlib driver, which is what lets LoadFile
+// drop down to the native *pgx.Conn for CopyFrom below.
+func (postgresDialect) OpenDB(dsn string) (*sql.DB, error) {
+	return sql.Open("pgx", dsn)
+}
+
+// LoadFile loads filename into tablename over a live connection, using
+// pgx's native CopyFrom instead of the copy ... from stdin text this
+// dialect emits for generated .sql files.
+func (d postgresDialect) LoadFile(ctx context.Context, db *sql.DB, filename, tablename string, rawNames, columnNames []string, types []*fieldType) (int64, []int, error) {
+	f, r, err := openCSVSource(filename)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	if !noHeader {
+		if _, err := r.Read(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer conn.Close()
+
+	var rowsLoaded int64
+	var errorRows []int
+	rowNum := 0
+	err = conn.Raw(func(driverConn any) error {
+		pgxConn := driverConn.(*stdlib.Conn).Conn()
+		tx, err := pgxConn.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		if clean {
+			if _, err := tx.Exec(ctx, fmt.Sprintf("drop table if exists %s", tablename)); err != nil {
+				return err
+			}
+		}
+		var ddl bytes.Buffer
+		if err := d.tableDDL(&ddl, tablename, rawNames, columnNames, types); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, ddl.String()); err != nil {
+			return err
+		}
+
+		// Values are handed to CopyFrom as already-translated text; pgx
+		// encodes them for whatever the destination column type turns
+		// out to be.
+		source := pgx.CopyFromFunc(func() ([]any, error) {
+			row, err := r.Read()
+			if err == io.EOF {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			rowNum++
+			vals := make([]any, len(columnNames))
+			for i, ft := range types {
+				if ft.SourceIndex >= len(row) || isNullValue(row[ft.SourceIndex]) {
+					continue
+				}
+				v, perr := ft.Parse(row[ft.SourceIndex])
+				if perr != nil {
+					errorRows = append(errorRows, rowNum)
+					vals[i] = row[ft.SourceIndex]
+					continue
+				}
+				vals[i] = v
+			}
+			return vals, nil
+		})
+
+		n, err := tx.CopyFrom(ctx, pgx.Identifier{tablename}, columnNames, source)
+		if err != nil {
+			return err
+		}
+		rowsLoaded = n
+		return tx.Commit(ctx)
+	})
+	return rowsLoaded, errorRows, err
+}
+
+// copyOptions builds the csv-format options for the copy statement so
+// postgres parses the payload written by translateRows the same way the
+// source file itself was parsed: same delimiter, quote character and NULL
+// marker.
+func copyOptions() string {
+	// No header option: translateRows never writes the header row into
+	// the payload, only data rows.
+	parts := []string{"csv"}
+	parts = append(parts, fmt.Sprintf("delimiter '%s'", escapeSQLString(delimiter)))
+	parts = append(parts, fmt.Sprintf("quote '%s'", escapeSQLString(quote)))
+	if nullString != "" {
+		parts = append(parts, fmt.Sprintf("null '%s'", escapeSQLString(nullString)))
+	}
+	return strings.Join(parts, " ")
+}
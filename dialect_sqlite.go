@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDialect targets the sqlite3 CLI: bulk data goes through a sidecar
+// CSV file loaded with the .import dot-command, since that's the only
+// bulk path the CLI offers.
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) QuoteIdent(name string) string { return name }
+
+func (sqliteDialect) MapType(f *fieldType) string {
+	mod := ""
+	if f.NotNull() {
+		mod = " not null"
+	}
+	if f.Override != "" {
+		return f.Override + mod
+	}
+	switch f.Kind() {
+	case kindDate:
+		return "TEXT" + mod
+	case kindBool:
+		return "INTEGER" + mod
+	case kindInt:
+		return "INTEGER" + mod
+	case kindFloat:
+		if precision, scale, ok := f.Precision(); ok {
+			return fmt.Sprintf("NUMERIC(%d,%d)%s", precision, scale, mod)
+		}
+		return "REAL" + mod
+	case kindUUID:
+		return "TEXT" + mod
+	case kindJSON:
+		return "TEXT" + mod
+	default:
+		return "TEXT"
+	}
+}
+
+func (d sqliteDialect) CreateTable(out io.Writer, tablename string, rawNames, columnNames []string, types []*fieldType) error {
+	if _, err := fmt.Fprintf(out, "begin;\n\n"); err != nil {
+		return err
+	}
+	if err := d.tableDDL(out, tablename, rawNames, columnNames, types); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "commit;\n\n")
+	return err
+}
+
+// tableDDL writes just the (optional drop plus) create table statement,
+// with no surrounding transaction; see postgresDialect.tableDDL.
+func (d sqliteDialect) tableDDL(out io.Writer, tablename string, rawNames, columnNames []string, types []*fieldType) error {
+	if clean {
+		if _, err := fmt.Fprintf(out, "drop table if exists %s;\n", tablename); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(out, "create table %s (\n", tablename); err != nil {
+		return err
+	}
+	// SQLite's ALTER TABLE can only rename or add/drop a column, so unlike
+	// every other dialect here a --schema primary_key hint has to be
+	// declared inline in CREATE TABLE rather than as a later ALTER TABLE
+	// ADD PRIMARY KEY (constraintStatements's inlinePrimaryKey=true skips
+	// that statement for us).
+	var primaryKey []string
+	for i, ft := range types {
+		if ft.PrimaryKey {
+			primaryKey = append(primaryKey, d.QuoteIdent(columnNames[i]))
+		}
+	}
+	w := tabwriter.NewWriter(out, 4, 4, 1, ' ', 0)
+	for i, name := range rawNames {
+		comma := ","
+		if i == len(rawNames)-1 && len(primaryKey) == 0 {
+			comma = ""
+		}
+		if _, err := w.Write([]byte(fmt.Sprintf("\t%s\t%s%s\t-- %s\n", columnNames[i], d.MapType(types[i]), comma, name))); err != nil {
+			return err
+		}
+	}
+	if len(primaryKey) > 0 {
+		if _, err := w.Write([]byte(fmt.Sprintf("\tprimary key (%s)\n", strings.Join(primaryKey, ", ")))); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(out, ");\n\n"); err != nil {
+		return err
+	}
+	for _, stmt := range constraintStatements(d, tablename, columnNames, types, true) {
+		if _, err := fmt.Fprintf(out, "%s\n", stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sqliteDialect) BulkLoadPrologue(out io.Writer, basename, tablename string, columnNames []string) (RowWriter, error) {
+	return createSidecar(basename, tablename, columnNames)
+}
+
+func (sqliteDialect) BulkLoadEpilogue(out io.Writer, rw RowWriter) error {
+	sc, ok := rw.(*sidecarRowWriter)
+	if !ok {
+		return fmt.Errorf("sqlite dialect requires a sidecar row writer")
+	}
+	if err := sc.Close(); err != nil {
+		return err
+	}
+	// The data file never has a header row: inferSchema's header line was
+	// consumed separately, and translateRows only ever writes data rows.
+	nullValue := ""
+	if nullString != "" {
+		nullValue = fmt.Sprintf(".nullvalue '%s'\n", escapeSQLString(nullString))
+	}
+	_, err := fmt.Fprintf(out, ".mode csv\n%s.import '%s' %s\n\n", nullValue, sc.path, sc.tablename)
+	return err
+}
+
+// IncludeFile uses the sqlite3 CLI's .read dot-command, its equivalent of
+// psql's \i.
+func (sqliteDialect) IncludeFile(out io.Writer, path string) error {
+	_, err := fmt.Fprintf(out, ".read '%s'\n", path)
+	return err
+}
+
+func (sqliteDialect) Merge(out io.Writer, merge, mode string, columnNames []string, selects []string) error {
+	union := strings.Join(selects, "\nunion all\n")
+	switch mode {
+	case "view":
+		if _, err := fmt.Fprintf(out, "drop view if exists %s;\n", merge); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(out, "create view %s as\n%s;\n\n", merge, union)
+		return err
+	case "insert":
+		_, err := fmt.Fprintf(out, "insert into %s (%s)\n%s;\n\n", merge, strings.Join(columnNames, ", "), union)
+		return err
+	default:
+		if clean {
+			if _, err := fmt.Fprintf(out, "drop table if exists %s;\n", merge); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(out, "create table %s as\n%s;\n\n", merge, union)
+		return err
+	}
+}
+
+// OpenDB opens dsn, which for sqlite is simply a file path.
+func (sqliteDialect) OpenDB(dsn string) (*sql.DB, error) {
+	return sql.Open("sqlite", dsn)
+}
+
+// LoadFile loads filename into tablename over a live connection using a
+// prepared insert statement, batched inside one transaction.
+func (d sqliteDialect) LoadFile(ctx context.Context, db *sql.DB, filename, tablename string, rawNames, columnNames []string, types []*fieldType) (int64, []int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if clean {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("drop table if exists %s", tablename)); err != nil {
+			return 0, nil, err
+		}
+	}
+	var ddl bytes.Buffer
+	if err := d.tableDDL(&ddl, tablename, rawNames, columnNames, types); err != nil {
+		return 0, nil, err
+	}
+	if _, err := tx.ExecContext(ctx, ddl.String()); err != nil {
+		return 0, nil, err
+	}
+
+	placeholders := make([]string, len(columnNames))
+	for i := range placeholders {
+		placeholders[i] = "?"
+	}
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("insert into %s (%s) values (%s)",
+		tablename, strings.Join(columnNames, ", "), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer stmt.Close()
+
+	f, r, err := openCSVSource(filename)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	if !noHeader {
+		if _, err := r.Read(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var rows int64
+	var errorRows []int
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, errorRows, err
+		}
+		rows++
+		args := make([]any, len(columnNames))
+		for i, ft := range types {
+			if ft.SourceIndex >= len(row) || isNullValue(row[ft.SourceIndex]) {
+				continue
+			}
+			v, perr := ft.Parse(row[ft.SourceIndex])
+			if perr != nil {
+				errorRows = append(errorRows, int(rows))
+				args[i] = row[ft.SourceIndex]
+				continue
+			}
+			args[i] = v
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return rows, errorRows, err
+		}
+	}
+	return rows, errorRows, tx.Commit()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// decodedReader wraps r so that bytes in the named source encoding are
+// transcoded to UTF-8 before the CSV reader ever sees them. An empty name
+// (or "utf-8") is a no-op: r is returned unwrapped.
+func decodedReader(r io.Reader, name string) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "utf-8", "utf8":
+		return r, nil
+	case "latin1", "iso-8859-1", "iso8859-1":
+		return transform.NewReader(r, charmap.ISO8859_1.NewDecoder()), nil
+	case "gbk":
+		return transform.NewReader(r, simplifiedchinese.GBK.NewDecoder()), nil
+	case "utf-16", "utf16":
+		// BOMOverride defaults to UTF-8 but switches to UTF-16LE/BE the
+		// moment it sees a BOM, which is the common case for "UTF-16"
+		// exports from spreadsheet tools.
+		return transform.NewReader(r, unicode.BOMOverride(unicode.UTF8.NewDecoder())), nil
+	default:
+		return nil, fmt.Errorf("unknown --encoding %q", name)
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type fieldType struct {
+	Date     bool
+	DateTime bool
+	Int      bool
+	Float    bool
+	Percent  bool
+	Bool     bool
+	UUID     bool
+	JSON     bool
+	Empty    bool
+	NonEmpty bool
+
+	// IntDigits and FracDigits track the widest integer and fractional
+	// part seen across every row for a Float column, so MapType can emit
+	// numeric(p,s) instead of losing precision to a plain float.
+	IntDigits  int
+	FracDigits int
+
+	// SourceIndex is this column's position in the raw CSV row. It stays
+	// fixed even if a --schema hint drops other columns, so translateRows
+	// and the direct-load Dialects can still find the right field after
+	// dropped columns have shifted everything else's index.
+	SourceIndex int
+
+	// Override, set from a --schema hint, is literal SQL type text that
+	// wins over whatever Kind inferred. OverrideNotNull, also hint-driven,
+	// likewise wins over Empty. PrimaryKey, Unique and Index ask
+	// CreateTable to emit the matching constraint or index.
+	Override        string
+	OverrideNotNull *bool
+	PrimaryKey      bool
+	Unique          bool
+	Index           bool
+}
+
+func newFieldType() *fieldType {
+	return &fieldType{
+		Date:     true,
+		Int:      true,
+		Float:    true,
+		Percent:  true,
+		Bool:     true,
+		UUID:     true,
+		JSON:     true,
+		Empty:    false,
+		NonEmpty: false,
+	}
+}
+
+func (f fieldType) Parse(s string) (string, error) {
+	if s == "#DIV/0!" {
+		s = ""
+	}
+	if nullString != "" && s == nullString {
+		s = ""
+	}
+	if s == "" {
+		return s, nil
+	}
+	switch {
+	case f.Date:
+		v, _, err := parseDate(s)
+		return v, err
+	case f.Int:
+		return parseInt(s)
+	case f.Bool:
+		return parseBool(s)
+	case f.Float:
+		return parseFloat(s)
+	case f.Percent:
+		return parsePercent(s)
+	default:
+		return s, nil
+	}
+}
+
+func (f *fieldType) Check(s string) {
+	if s == "#DIV/0!" {
+		s = ""
+	}
+	if nullString != "" && s == nullString {
+		s = ""
+	}
+	if s == "" {
+		f.Empty = true
+		return
+	} else {
+		f.NonEmpty = true
+	}
+	if f.Date {
+		_, hasTime, err := parseDate(s)
+		if err != nil {
+			f.Date = false
+		} else if hasTime {
+			f.DateTime = true
+		}
+	}
+	if f.Bool {
+		if !isBool(s) {
+			f.Bool = false
+		}
+	}
+	if f.Int {
+		_, err := parseInt(s)
+		if err != nil {
+			f.Int = false
+		}
+	}
+	if f.Float {
+		_, err := parseFloat(s)
+		if err != nil {
+			f.Float = false
+		} else {
+			intDigits, fracDigits := countDigits(strings.ReplaceAll(s, ",", ""))
+			if intDigits > f.IntDigits {
+				f.IntDigits = intDigits
+			}
+			if fracDigits > f.FracDigits {
+				f.FracDigits = fracDigits
+			}
+		}
+	}
+	if f.Percent {
+		_, err := parsePercent(s)
+		if err != nil {
+			f.Percent = false
+		}
+	}
+	if f.UUID {
+		if !uuidRe.MatchString(s) {
+			f.UUID = false
+		}
+	}
+	if f.JSON {
+		if !isJSONValue(s) {
+			f.JSON = false
+		}
+	}
+}
+
+type fieldKind int
+
+const (
+	kindText fieldKind = iota
+	kindBool
+	kindInt
+	kindFloat
+	kindDate
+	kindUUID
+	kindJSON
+)
+
+// Kind reports the column kind Check settled on, for a Dialect's MapType to
+// translate into its own type names. Date wins over the more specific
+// textual kinds (UUID, JSON) because a column full of ISO dates would also
+// satisfy neither of those. Int is checked before Bool because isBool's
+// 0/1 heuristic would otherwise claim every plain integer column made up
+// exclusively of 0s and 1s, which is far more often a numeric flag column
+// than a boolean one.
+func (f fieldType) Kind() fieldKind {
+	switch {
+	case f.Date:
+		return kindDate
+	case f.Int:
+		return kindInt
+	case f.Bool:
+		return kindBool
+	case f.Float || f.Percent:
+		return kindFloat
+	case f.UUID:
+		return kindUUID
+	case f.JSON:
+		return kindJSON
+	default:
+		return kindText
+	}
+}
+
+// NotNull reports whether every row seen during Check had a value for this
+// column, unless a --schema hint overrides that.
+func (f fieldType) NotNull() bool {
+	if f.OverrideNotNull != nil {
+		return *f.OverrideNotNull
+	}
+	return !f.Empty
+}
+
+// Precision returns the numeric(p,s)-style precision and scale implied by
+// the widest value seen for a Float column, and whether that's meaningful
+// (a column that never matched Float, or matched but never saw any digits,
+// has nothing to report).
+func (f fieldType) Precision() (precision, scale int, ok bool) {
+	if !f.Float || (f.IntDigits == 0 && f.FracDigits == 0) {
+		return 0, 0, false
+	}
+	return f.IntDigits + f.FracDigits, f.FracDigits, true
+}
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isJSONValue reports whether s is valid JSON *and* looks like it was
+// meant as one: json.Valid alone accepts bare numbers, strings and
+// booleans, which would otherwise tag every ordinary column as JSON too.
+func isJSONValue(s string) bool {
+	t := strings.TrimSpace(s)
+	if t == "" || (t[0] != '{' && t[0] != '[') {
+		return false
+	}
+	return json.Valid([]byte(t))
+}
+
+func isBool(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "t", "f", "1", "0":
+		return true
+	default:
+		return false
+	}
+}
+
+func parseBool(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "true", "yes", "t", "1":
+		return "true", nil
+	case "false", "no", "f", "0":
+		return "false", nil
+	default:
+		return "", errors.New("not a boolean")
+	}
+}
+
+// countDigits returns the number of digits before and after the decimal
+// point in s, ignoring a leading sign and expanding out an "e"/"E"
+// exponent first: "1.5e10" is 15000000000, 11 integer digits and none
+// fractional, not the 1 and 4 its literal text would suggest.
+func countDigits(s string) (intDigits, fracDigits int) {
+	s = strings.TrimPrefix(s, "-")
+	s = strings.TrimPrefix(s, "+")
+	mantissa := s
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa = s[:i]
+		exp, _ = strconv.Atoi(s[i+1:])
+	}
+	whole, frac, hasFrac := strings.Cut(mantissa, ".")
+	digits := whole
+	if hasFrac {
+		digits += frac
+	}
+	point := len(whole) + exp
+	switch {
+	case point >= len(digits):
+		intDigits = point
+	case point <= 0:
+		intDigits = 1
+		fracDigits = len(digits) - point
+	default:
+		intDigits = point
+		fracDigits = len(digits) - point
+	}
+	return
+}
+
+// parseDate tries, in order, the user-supplied --date-format layouts, the
+// built-in date-only layouts, the user-supplied --datetime-format layouts,
+// the built-in timestamp layouts, and finally an epoch seconds/millis
+// heuristic. It reports whether the matched layout carried a time
+// component, so Check can tell a plain date column from a timestamp one.
+func parseDate(s string) (value string, hasTime bool, err error) {
+	for _, layout := range append(append([]string{}, dateFormats...), builtinDateLayouts...) {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(time.RFC3339), false, nil
+		}
+	}
+	for _, layout := range append(append([]string{}, datetimeFormats...), builtinDateTimeLayouts...) {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(time.RFC3339), true, nil
+		}
+	}
+	if allowEpoch {
+		if t, ok := parseEpoch(s); ok {
+			return t.Format(time.RFC3339), true, nil
+		}
+	}
+	return "", false, errors.New("not a timestamp")
+}
+
+var builtinDateLayouts = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+}
+
+var builtinDateTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// parseEpoch recognises plain 10-digit (seconds) or 13-digit
+// (milliseconds) unix timestamps, which is the conventional width for
+// dates from 2001 to 2286 in either unit. Only parseDate
+// calls this, and only when --epoch-dates is set: an all-numeric string
+// of that width is just as often a phone number, barcode or order ID, so
+// this heuristic is opt-in rather than tried against every column.
+func parseEpoch(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return time.Time{}, false
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	switch len(s) {
+	case 13:
+		return time.UnixMilli(n).UTC(), true
+	case 10:
+		return time.Unix(n, 0).UTC(), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func parseInt(s string) (string, error) {
+	s = strings.ReplaceAll(s, ",", "")
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(n), nil
+}
+
+// parseFloat validates s as a float but, unlike parseBool/parseInt, returns
+// the cleaned source text rather than a value reformatted from the parsed
+// float: fmt.Sprintf("%f") fixes every column to 6 decimal places, silently
+// discarding or padding precision that Check's IntDigits/FracDigits (and so
+// Precision/MapType's numeric(p,s)) already promised to keep.
+func parseFloat(s string) (string, error) {
+	s = strings.ReplaceAll(s, ",", "")
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+func parsePercent(s string) (string, error) {
+	if !strings.HasSuffix(s, "%") {
+		return "", errors.New("no trailing percent")
+	}
+	s = strings.TrimSuffix(s, "%")
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%f", f), nil
+}
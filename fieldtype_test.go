@@ -0,0 +1,95 @@
+package main
+
+import "testing"
+
+func TestParseEpochGatedByFlag(t *testing.T) {
+	defer func(v bool) { allowEpoch = v }(allowEpoch)
+
+	allowEpoch = false
+	if _, _, err := parseDate("4155551234"); err == nil {
+		t.Fatalf("parseDate(%q) should not match as a timestamp with --epoch-dates off", "4155551234")
+	}
+
+	allowEpoch = true
+	if _, hasTime, err := parseDate("4155551234"); err != nil || !hasTime {
+		t.Fatalf("parseDate(%q) with --epoch-dates on: got hasTime=%v err=%v, want a timestamp", "4155551234", hasTime, err)
+	}
+}
+
+func TestNullStringRecognizedOnInput(t *testing.T) {
+	defer func(v string) { nullString = v }(nullString)
+	nullString = "NA"
+
+	f := newFieldType()
+	f.Check("123")
+	f.Check("NA")
+	f.Check("456")
+	if !f.Int {
+		t.Fatalf("column should still infer as Int once NA is treated as NULL, got %+v", f)
+	}
+	if !f.Empty {
+		t.Fatalf("Check(%q) should have marked the column Empty", "NA")
+	}
+
+	v, err := f.Parse("NA")
+	if err != nil || v != "" {
+		t.Errorf("Parse(%q) = (%q, %v), want (\"\", nil)", "NA", v, err)
+	}
+}
+
+func TestCountDigits(t *testing.T) {
+	cases := []struct {
+		in         string
+		intDigits  int
+		fracDigits int
+	}{
+		{"123", 3, 0},
+		{"3.14", 1, 2},
+		{"-3.14", 1, 2},
+		{"0.0015", 1, 4},
+		{"1.5e10", 11, 0},
+		{"1.5e-3", 1, 4},
+		{"1.23456789e2", 3, 6},
+	}
+	for _, c := range cases {
+		intDigits, fracDigits := countDigits(c.in)
+		if intDigits != c.intDigits || fracDigits != c.fracDigits {
+			t.Errorf("countDigits(%q) = (%d, %d), want (%d, %d)", c.in, intDigits, fracDigits, c.intDigits, c.fracDigits)
+		}
+	}
+}
+
+func TestParseFloatPreservesPrecision(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"3.123456789", "3.123456789"},
+		{"1,234.50", "1234.50"},
+		{"1.5e10", "1.5e10"},
+	}
+	for _, c := range cases {
+		got, err := parseFloat(c.in)
+		if err != nil {
+			t.Fatalf("parseFloat(%q) returned error: %v", c.in, err)
+		}
+		if got != c.want {
+			t.Errorf("parseFloat(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestFloatColumnPrecisionMatchesParse(t *testing.T) {
+	f := newFieldType()
+	f.Check("3.123456789")
+	precision, scale, ok := f.Precision()
+	if !ok {
+		t.Fatalf("Precision() reported not ok for a float column")
+	}
+	v, err := f.Parse("3.123456789")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	intDigits, fracDigits := countDigits(v)
+	if intDigits+fracDigits > precision || fracDigits > scale {
+		t.Errorf("Parse(%q) = %q needs precision(%d,%d) but Precision() only promised (%d,%d)",
+			"3.123456789", v, intDigits+fracDigits, fracDigits, precision, scale)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// openCSVSource opens filename, applies the configured source encoding and
+// skip-rows/delimiter settings, and returns a csv.Reader positioned right
+// before the first row the caller cares about. The caller is responsible
+// for closing the returned file.
+func openCSVSource(filename string) (*os.File, *csv.Reader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	src, err := decodedReader(f, encoding)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	r := csv.NewReader(src)
+	r.LazyQuotes = true
+	if delimiter != "" {
+		r.Comma = []rune(delimiter)[0]
+	}
+	for i := 0; i < skipRows; i++ {
+		if _, err := r.Read(); err != nil {
+			_ = f.Close()
+			return nil, nil, err
+		}
+	}
+	return f, r, nil
+}
+
+func slugColumns(header []string) []string {
+	columnNames := make([]string, len(header))
+	columnSeen := map[string]struct{}{}
+	for i, col := range header {
+		sl := slug(col)
+		if sl == "" {
+			sl = "x"
+		}
+		if _, ok := columnSeen[sl]; ok {
+			suffix := 2
+			for {
+				candidate := fmt.Sprintf("%s_%d", sl, suffix)
+				if _, ok := columnSeen[candidate]; !ok {
+					sl = candidate
+					break
+				}
+				suffix++
+			}
+		}
+		columnSeen[sl] = struct{}{}
+		columnNames[i] = sl
+	}
+	return columnNames
+}
+
+// isNullValue reports whether col is this run's NULL marker: either the
+// field was simply absent from the row, or it held the literal text
+// --null-string says represents NULL in the source data.
+func isNullValue(col string) bool {
+	return col == "" || (nullString != "" && col == nullString)
+}
+
+func checkRow(types []*fieldType, row []string) {
+	for i, field := range row {
+		if i < len(types) {
+			types[i].Check(field)
+		}
+	}
+}
+
+// inferSchema makes a first streaming pass over filename, reading rows with
+// Read in a loop rather than ReadAll, so working out column types doesn't
+// require the whole file to fit in memory.
+func inferSchema(filename string) (rawNames, columnNames []string, types []*fieldType, err error) {
+	f, r, err := openCSVSource(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	var firstDataRow []string
+	if noHeader {
+		row, err := r.Read()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rawNames = make([]string, len(row))
+		for i := range row {
+			rawNames[i] = fmt.Sprintf("col_%d", i+1)
+		}
+		columnNames = append([]string{}, rawNames...)
+		firstDataRow = row
+	} else {
+		header, err := r.Read()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rawNames = header
+		columnNames = slugColumns(header)
+	}
+	types = make([]*fieldType, len(rawNames))
+	for i := range types {
+		types[i] = newFieldType()
+		types[i].SourceIndex = i
+	}
+	rowsSeen := 0
+	if firstDataRow != nil {
+		// The row we read above to discover the column count is itself data.
+		checkRow(types, firstDataRow)
+		rowsSeen++
+	}
+
+	for sampleRows <= 0 || rowsSeen < sampleRows {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		checkRow(types, row)
+		rowsSeen++
+	}
+	return rawNames, columnNames, types, nil
+}
+
+// handle converts filename into a .sql file next to it: a create table
+// statement derived from inferSchema, followed by the bulk-load statement(s)
+// the active dialect uses to get the translated rows back in. Both passes
+// stream the CSV rather than holding it in memory, so multi-GB files import
+// without OOMing. It returns the table's final name and schema, so a
+// --merge pass doesn't have to re-infer it.
+func handle(filename string) (tablename string, columnNames []string, types []*fieldType, err error) {
+	rawNames, columnNames, types, err := inferSchema(filename)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if schemaHints != nil {
+		rawNames, columnNames, types = applySchemaHints(schemaHints, rawNames, columnNames, types)
+	}
+
+	basename := strings.TrimSuffix(filename, ".csv")
+	tablename = slug(basename)
+	outfile := basename + ".sql"
+	out, err := os.Create(outfile)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	defer out.Close()
+
+	_, _ = fmt.Fprintf(out, "-- -*-sql-*-\n-- Created from %s\n\n", filename)
+	if err := dialect.CreateTable(out, tablename, rawNames, columnNames, types); err != nil {
+		return "", nil, nil, err
+	}
+
+	rw, err := dialect.BulkLoadPrologue(out, basename, tablename, columnNames)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if err := translateRows(filename, types, rw); err != nil {
+		return "", nil, nil, err
+	}
+	if err := dialect.BulkLoadEpilogue(out, rw); err != nil {
+		return "", nil, nil, err
+	}
+	return tablename, columnNames, types, nil
+}
+
+// translateRows makes a second streaming pass over filename, re-opening it
+// and translating each row through the now-known fieldTypes straight into
+// rw, so the whole file never has to be held in memory at once. rw is
+// whatever sink the active dialect's BulkLoadPrologue handed back: the SQL
+// file itself, or a sidecar data file.
+func translateRows(filename string, types []*fieldType, rw RowWriter) error {
+	f, r, err := openCSVSource(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if !noHeader {
+		if _, err := r.Read(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		fields := make([]string, len(types))
+		for i, ft := range types {
+			col := ""
+			if ft.SourceIndex < len(row) {
+				col = row[ft.SourceIndex]
+			}
+			v, err := ft.Parse(col)
+			if err != nil {
+				log.Fatalf("internal error handling [%s] in column %d", col, i)
+			}
+			if isNullValue(col) && nullString != "" {
+				v = nullString
+			}
+			fields[i] = v
+		}
+		if err := rw.WriteRow(fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// translateRowsCounted is translateRows' counterpart for --dsn direct
+// loading: instead of aborting on the first unparseable field, it loads that
+// field's raw text and records the (1-based) row number so the caller can
+// report it, since a live load has no generated .sql file for the user to
+// go back and inspect.
+func translateRowsCounted(filename string, types []*fieldType, rw RowWriter) (rows int64, errorRows []int, err error) {
+	f, r, err := openCSVSource(filename)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	if !noHeader {
+		if _, err := r.Read(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, errorRows, err
+		}
+		rows++
+		fields := make([]string, len(types))
+		for i, ft := range types {
+			col := ""
+			if ft.SourceIndex < len(row) {
+				col = row[ft.SourceIndex]
+			}
+			v, perr := ft.Parse(col)
+			if perr != nil {
+				errorRows = append(errorRows, int(rows))
+				fields[i] = col
+				continue
+			}
+			if isNullValue(col) && nullString != "" {
+				v = nullString
+			}
+			fields[i] = v
+		}
+		if err := rw.WriteRow(fields); err != nil {
+			return rows, errorRows, err
+		}
+	}
+	return rows, errorRows, nil
+}
+
+func slug(s string) string {
+	punctRe := regexp.MustCompile(`[^a-z0-9]+`)
+	sl := punctRe.ReplaceAllString(strings.ToLower(s), "_")
+	return strings.Trim(sl, "_")
+}
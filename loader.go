@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// DirectLoader is implemented by dialects that can load a CSV straight
+// into a live database connection (--dsn) instead of only generating the
+// .sql script that handle writes out.
+type DirectLoader interface {
+	// OpenDB opens dsn with whatever driver this dialect needs.
+	OpenDB(dsn string) (*sql.DB, error)
+	// LoadFile creates tablename (honoring --clean) and bulk-loads
+	// filename into it over db, all inside one transaction. It reports
+	// the number of rows loaded and the (1-based) row numbers that
+	// failed to parse and were loaded as their raw text instead.
+	LoadFile(ctx context.Context, db *sql.DB, filename, tablename string, rawNames, columnNames []string, types []*fieldType) (rowsLoaded int64, errorRows []int, err error)
+}
+
+// runDirectLoad is main's --dsn entry point. It bypasses the .sql /
+// alltables.sql generation path entirely and streams each file straight
+// into dsn using the active dialect's driver-native bulk load.
+func runDirectLoad(dsn string, files []string) error {
+	loader, ok := dialect.(DirectLoader)
+	if !ok {
+		return fmt.Errorf("--dialect %s does not support --dsn direct loading", dialect.Name())
+	}
+	db, err := loader.OpenDB(dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	for _, f := range files {
+		rawNames, columnNames, types, err := inferSchema(f)
+		if err != nil {
+			log.Printf("Failed to infer schema for %s: %s", f, err)
+			continue
+		}
+		if schemaHints != nil {
+			rawNames, columnNames, types = applySchemaHints(schemaHints, rawNames, columnNames, types)
+		}
+		tablename := slug(strings.TrimSuffix(f, ".csv"))
+		rows, errorRows, err := loader.LoadFile(ctx, db, f, tablename, rawNames, columnNames, types)
+		if err != nil {
+			log.Printf("Failed to load %s: %s", f, err)
+			continue
+		}
+		if len(errorRows) > 0 {
+			log.Printf("%s: loaded %d rows into %s, %d row(s) failed to parse: %v", f, rows, tablename, len(errorRows), errorRows)
+		} else {
+			log.Printf("%s: loaded %d rows into %s", f, rows, tablename)
+		}
+	}
+	return nil
+}
@@ -1,283 +1,134 @@
 package main
 
 import (
-	"encoding/csv"
-	"errors"
 	"fmt"
 	flag "github.com/spf13/pflag"
 	"log"
 	"os"
-	"regexp"
-	"strconv"
 	"strings"
-	"text/tabwriter"
-	"time"
 )
 
-var clean bool
-var merge string
+var (
+	clean           bool
+	merge           string
+	delimiter       string
+	quote           string
+	encoding        string
+	noHeader        bool
+	nullString      string
+	skipRows        int
+	dialectName     string
+	dialect         Dialect
+	dsn             string
+	dateFormats     []string
+	datetimeFormats []string
+	sampleRows      int
+	schemaPath      string
+	schemaHints     *schemaHintsFile
+	emitSchema      bool
+	mergeSourceCol  bool
+	mergeMode       string
+	allowEpoch      bool
+)
 
 func main() {
 	flag.BoolVar(&clean, "clean", false, "Drop tables before recreating them")
-	flag.StringVar(&merge, "merge", "", "Attempt to merge all imported data into this table")
+	flag.StringVar(&merge, "merge", "", "Attempt to merge all imported data into this table, reconciling schemas across files")
+	flag.BoolVar(&mergeSourceCol, "merge-source", false, "Add a source_file column to the merge recording which file each row came from")
+	flag.StringVar(&mergeMode, "merge-mode", "table", "How to build --merge: view, table (create table as select), or insert (into an existing table)")
+	flag.StringVar(&delimiter, "delimiter", ",", "Field delimiter character")
+	flag.StringVar(&quote, "quote", `"`, "Quote character (currently fixed to \" -- encoding/csv has no configurable quote rune, so any other value is rejected rather than silently ignored)")
+	flag.StringVar(&encoding, "encoding", "utf-8", "Source file character encoding (utf-8, latin1, gbk, utf-16)")
+	flag.BoolVar(&noHeader, "no-header", false, "CSV file has no header row; columns are named col_1, col_2, ...")
+	flag.StringVar(&nullString, "null-string", "", "String that represents NULL in the source data, recognized on input and written back out as each dialect's NULL marker")
+	flag.IntVar(&skipRows, "skip-rows", 0, "Number of rows to skip before the header")
+	flag.StringVar(&dialectName, "dialect", "postgres", "Target database dialect (postgres, mysql, sqlite, mssql)")
+	flag.StringVar(&dsn, "dsn", "", "Load straight into this database connection instead of writing .sql files")
+	flag.StringArrayVar(&dateFormats, "date-format", nil, "Additional date layout to accept, as a Go reference-time format; repeatable")
+	flag.StringArrayVar(&datetimeFormats, "datetime-format", nil, "Additional timestamp layout to accept, as a Go reference-time format; repeatable")
+	flag.BoolVar(&allowEpoch, "epoch-dates", false, "Also infer a plain 10- or 13-digit all-numeric column as a unix epoch seconds/millis timestamp (off by default: phone numbers, barcodes and order IDs are the same shape)")
+	flag.IntVar(&sampleRows, "sample", 0, "Only scan the first N rows to infer column types (0 means scan every row)")
+	flag.StringVar(&schemaPath, "schema", "", "YAML or JSON file of per-column overrides (name, type, nullability, primary key, unique, index, skip)")
+	flag.BoolVar(&emitSchema, "emit-schema", false, "Instead of importing, write each file's inferred schema out as a --schema hints file to hand-edit and re-feed")
 	flag.Parse()
-	sqlFiles := []string{}
-	for _, f := range flag.Args() {
-		err := handle(f)
-		if err != nil {
-			log.Printf("Failed to handle %s: %s", f, err)
-		} else {
-			sqlFiles = append(sqlFiles, strings.TrimSuffix(f, ".csv")+".sql")
-		}
+
+	// encoding/csv's Reader and Writer both hardcode the quote character to
+	// '"'; there's no way to make either of them honor a different one, so
+	// rather than emit COPY/LOAD DATA options that lie about the quoting
+	// csvimport itself actually performed, refuse to start.
+	if quote != `"` {
+		log.Fatalf(`--quote %q is not supported: encoding/csv always reads and writes "-quoted fields`, quote)
 	}
-	all, err := os.Create("alltables.sql")
+
+	var err error
+	dialect, err = pickDialect(dialectName)
 	if err != nil {
 		log.Fatal(err)
 	}
-	_, _ = fmt.Fprintf(all, "-- -*-sql-*-\n")
-	for _, file := range sqlFiles {
-		_, _ = fmt.Fprintf(all, "\\i '%s'\n", file)
-	}
-	if merge != "" {
-		if clean {
-			_, _ = fmt.Fprintf(all, "drop table if exists %s;\n", merge)
-		}
-		selects := make([]string, len(sqlFiles))
-		for i, t := range sqlFiles {
-			selects[i] = fmt.Sprintf("select * from %s\n", slug(strings.TrimSuffix(t, ".sql")))
-		}
-		_, _ = fmt.Fprintf(all, "create table %s as\n%s;", merge, strings.Join(selects, "union\n"))
-	}
-
-	_ = all.Close()
-}
-
-type fieldType struct {
-	Date     bool
-	Int      bool
-	Float    bool
-	Percent  bool
-	Empty    bool
-	NonEmpty bool
-}
-
-func newFieldType() *fieldType {
-	return &fieldType{
-		Date:     true,
-		Int:      true,
-		Float:    true,
-		Percent:  true,
-		Empty:    false,
-		NonEmpty: false,
-	}
-}
 
-func (f fieldType) Parse(s string) (string, error) {
-	if s == "#DIV/0!" {
-		s = ""
-	}
-	if s == "" {
-		return s, nil
-	}
-	switch {
-	case f.Date:
-		return parseDate(s)
-	case f.Int:
-		return parseInt(s)
-	case f.Float:
-		return parseFloat(s)
-	case f.Percent:
-		return parsePercent(s)
+	switch mergeMode {
+	case "view", "table", "insert":
 	default:
-		return s, nil
+		log.Fatalf("unknown --merge-mode %q", mergeMode)
 	}
-}
 
-func (f *fieldType) Check(s string) {
-	if s == "#DIV/0!" {
-		s = ""
-	}
-	if s == "" {
-		f.Empty = true
-		return
-	} else {
-		f.NonEmpty = true
-	}
-	if f.Date {
-		_, err := parseDate(s)
-		if err != nil {
-			f.Date = false
-		}
-	}
-	if f.Int {
-		_, err := parseInt(s)
+	if schemaPath != "" {
+		schemaHints, err = loadSchemaHints(schemaPath)
 		if err != nil {
-			f.Int = false
+			log.Fatal(err)
 		}
 	}
-	if f.Float {
-		_, err := parseFloat(s)
-		if err != nil {
-			f.Float = false
-		}
-	}
-	if f.Percent {
-		_, err := parsePercent(s)
-		if err != nil {
-			f.Percent = false
-		}
-	}
-}
-
-func (f fieldType) SqlType() string {
-	mod := ""
-	if !f.Empty {
-		mod = " not null"
-	}
-	if f.Date {
-		return "timestamptz" + mod
-	}
-	if f.Int {
-		return "integer" + mod
-	}
-	if f.Float || f.Percent {
-		return "float" + mod
-	}
-	return "text"
-}
 
-func parseDate(s string) (string, error) {
-	layouts := []string{
-		"2006-01-02",
-	}
-	for _, layout := range layouts {
-		t, err := time.Parse(layout, s)
-		if err == nil {
-			return t.Format(time.RFC3339), nil
+	if emitSchema {
+		for _, f := range flag.Args() {
+			if err := emitSchemaFor(f); err != nil {
+				log.Printf("Failed to emit schema for %s: %s", f, err)
+			}
 		}
-	}
-	return "", errors.New("not a timestamp")
-}
-
-func parseInt(s string) (string, error) {
-	s = strings.ReplaceAll(s, ",", "")
-	n, err := strconv.Atoi(s)
-	if err != nil {
-		return "", err
-	}
-	return strconv.Itoa(n), nil
-}
-
-func parseFloat(s string) (string, error) {
-	s = strings.ReplaceAll(s, ",", "")
-	f, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return "", err
-	}
-	return fmt.Sprintf("%f", f), nil
-}
-
-func parsePercent(s string) (string, error) {
-	if !strings.HasSuffix(s, "%") {
-		return "", errors.New("no trailing percent")
-	}
-	s = strings.TrimSuffix(s, "%")
-	f, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return "", err
-	}
-	return fmt.Sprintf("%f", f), nil
-}
-
-func handle(filename string) error {
-	in, err := os.Open(filename)
-	if err != nil {
-		return err
-	}
-	outfile := strings.TrimSuffix(filename, ".csv") + ".sql"
-	out, err := os.Create(outfile)
-	if err != nil {
-		return err
+		return
 	}
 
-	r := csv.NewReader(in)
-	r.LazyQuotes = true
-	records, err := r.ReadAll()
-	if err != nil {
-		return err
-	}
-	types := make([]*fieldType, len(records[0]))
-	columnNames := make([]string, len(records[0]))
-	columnSeen := map[string]struct{}{}
-	for i, col := range records[0] {
-		types[i] = newFieldType()
-		sl := slug(col)
-		if sl == "" {
-			sl = "x"
-		}
-		_, ok := columnSeen[sl]
-		if ok {
-			i := 2
-			for {
-				suffix := fmt.Sprintf("_%d", i)
-				_, ok := columnSeen[sl+suffix]
-				if !ok {
-					sl += suffix
-					break
-				}
-				i++
-			}
+	if dsn != "" {
+		if err := runDirectLoad(dsn, flag.Args()); err != nil {
+			log.Fatal(err)
 		}
-		columnSeen[sl] = struct{}{}
-		columnNames[i] = sl
+		return
 	}
 
-	for _, row := range records[1:] {
-		for i, field := range row {
-			types[i].Check(field)
+	sqlFiles := []string{}
+	var mergeSources []mergeSource
+	for _, f := range flag.Args() {
+		tablename, columnNames, types, err := handle(f)
+		if err != nil {
+			log.Printf("Failed to handle %s: %s", f, err)
+			continue
+		}
+		sqlFiles = append(sqlFiles, strings.TrimSuffix(f, ".csv")+".sql")
+		if merge != "" {
+			mergeSources = append(mergeSources, mergeSource{filename: f, tablename: tablename, columnNames: columnNames, types: types})
 		}
 	}
-
-	tablename := slug(strings.TrimSuffix(filename, ".csv"))
-
-	_, _ = fmt.Fprintf(out, "-- -*-sql-*-\n-- Created from %s\n\nbegin;\n\n", filename)
-	if clean {
-		_, _ = fmt.Fprintf(out, "drop table if exists %s;\n", tablename)
+	all, err := os.Create("alltables.sql")
+	if err != nil {
+		log.Fatal(err)
 	}
-	_, _ = fmt.Fprintf(out, "create table %s (\n", tablename)
-	w := tabwriter.NewWriter(out, 4, 4, 1, ' ', 0)
-	for i, name := range records[0] {
-		comma := ","
-		if i == len(records[0])-1 {
-			comma = ""
+	_, _ = fmt.Fprintf(all, "-- -*-sql-*-\n")
+	for _, file := range sqlFiles {
+		if err := dialect.IncludeFile(all, file); err != nil {
+			log.Fatal(err)
 		}
-		_, _ = w.Write([]byte(fmt.Sprintf("\t%s\t%s%s\t-- %s\n", columnNames[i], types[i].SqlType(), comma, name)))
 	}
-	_ = w.Flush()
-	_, _ = fmt.Fprintf(out, ");\n\n")
-	_, _ = fmt.Fprintf(out, "copy %s (%s) from stdin csv header;\n", tablename, strings.Join(columnNames, ", "))
-	csvWriter := csv.NewWriter(out)
-	for _, row := range records[1:] {
-		fields := make([]string, len(row))
-		for i, col := range row {
-			fields[i], err = types[i].Parse(col)
-			if err != nil {
-				log.Fatalf("internal error handling [%s] in column %d", col, i)
-			}
+	if merge != "" {
+		columns := reconcileMergeSchema(mergeSources)
+		selects := make([]string, len(mergeSources))
+		for i, src := range mergeSources {
+			selects[i] = buildMergeSelect(dialect, src, columns, mergeSourceCol)
 		}
-		err := csvWriter.Write(fields)
-		if err != nil {
-			return err
+		columnNames := mergeColumnNames(columns, mergeSourceCol)
+		if err := dialect.Merge(all, merge, mergeMode, columnNames, selects); err != nil {
+			log.Fatal(err)
 		}
 	}
-	csvWriter.Flush()
-	_, _ = fmt.Fprintf(out, "\\.\n\ncommit;\n\n")
 
-	return out.Close()
-}
-
-func slug(s string) string {
-	punctRe := regexp.MustCompile(`[^a-z0-9]+`)
-	sl := punctRe.ReplaceAllString(strings.ToLower(s), "_")
-	return strings.Trim(sl, "_")
+	_ = all.Close()
 }
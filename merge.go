@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mergeSource is one table handle produced, carried through to the --merge
+// pass so it doesn't have to re-infer each file's schema.
+type mergeSource struct {
+	filename    string
+	tablename   string
+	columnNames []string
+	types       []*fieldType
+}
+
+// mergeColumn is one column of the reconciled --merge schema: the widest
+// kind and nullability seen for that column name across every source table.
+type mergeColumn struct {
+	name     string
+	kind     fieldKind
+	dateTime bool
+	notNull  bool
+}
+
+// reconcileMergeSchema computes the union of column names across sources,
+// in the stable order they're first seen, widening each one's type
+// (int∪float→float, anything∪text→text) and nullability (nullable if any
+// source is nullable, or if any source doesn't have the column at all)
+// across every source that has it.
+func reconcileMergeSchema(sources []mergeSource) []mergeColumn {
+	var order []string
+	seen := map[string]*mergeColumn{}
+	for _, src := range sources {
+		for i, name := range src.columnNames {
+			ft := src.types[i]
+			col, ok := seen[name]
+			if !ok {
+				col = &mergeColumn{name: name, kind: ft.Kind(), dateTime: ft.DateTime, notNull: true}
+				seen[name] = col
+				order = append(order, name)
+			} else {
+				col.kind = widenKind(col.kind, ft.Kind())
+				col.dateTime = col.dateTime || ft.DateTime
+			}
+			if !ft.NotNull() {
+				col.notNull = false
+			}
+		}
+	}
+	// A column absent from some source has to read back as NULL there, so
+	// it can't be NOT NULL in the merged schema.
+	for _, col := range seen {
+		for _, src := range sources {
+			if !containsString(src.columnNames, col.name) {
+				col.notNull = false
+				break
+			}
+		}
+	}
+	columns := make([]mergeColumn, len(order))
+	for i, name := range order {
+		columns[i] = *seen[name]
+	}
+	return columns
+}
+
+// widenKind resolves a's and b's fieldKinds to the narrowest kind that can
+// represent both: matching kinds pass through, int widens to float
+// alongside a float, and anything else (e.g. date∪bool) falls back to text
+// rather than risk a lossy or failing cast.
+func widenKind(a, b fieldKind) fieldKind {
+	if a == b {
+		return a
+	}
+	if a == kindText || b == kindText {
+		return kindText
+	}
+	if (a == kindInt && b == kindFloat) || (a == kindFloat && b == kindInt) {
+		return kindFloat
+	}
+	return kindText
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// syntheticFieldType builds a fieldType whose Kind()/DateTime/NotNull()
+// reproduce col, so dialect.MapType can render its merged SQL type the same
+// way it renders any inferred column's.
+func syntheticFieldType(col mergeColumn) *fieldType {
+	ft := &fieldType{}
+	switch col.kind {
+	case kindBool:
+		ft.Bool = true
+	case kindInt:
+		ft.Int = true
+	case kindFloat:
+		ft.Float = true
+	case kindDate:
+		ft.Date = true
+		ft.DateTime = col.dateTime
+	case kindUUID:
+		ft.UUID = true
+	case kindJSON:
+		ft.JSON = true
+	}
+	notNull := col.notNull
+	ft.OverrideNotNull = &notNull
+	return ft
+}
+
+// mergeType renders col's merged SQL type (with no NOT NULL suffix: the
+// projected SELECT needs the bare type for its casts, and the merge table
+// itself is built by the dialect's own CREATE TABLE/VIEW statement).
+func mergeType(d Dialect, col mergeColumn) string {
+	return strings.TrimSuffix(d.MapType(syntheticFieldType(col)), " not null")
+}
+
+// buildMergeSelect renders src's branch of the --merge UNION: columns it
+// has are selected (cast to the merged type when its own column's type
+// differs), columns it lacks are projected as a typed NULL, in the same
+// stable order for every source so the UNION lines up. When mergeSourceCol
+// is true, a source_file literal naming src.filename is appended.
+func buildMergeSelect(d Dialect, src mergeSource, columns []mergeColumn, includeSourceFile bool) string {
+	srcTypes := map[string]*fieldType{}
+	for i, name := range src.columnNames {
+		srcTypes[name] = src.types[i]
+	}
+
+	parts := make([]string, 0, len(columns)+1)
+	for _, col := range columns {
+		merged := mergeType(d, col)
+		if ft, ok := srcTypes[col.name]; ok {
+			if strings.TrimSuffix(d.MapType(ft), " not null") == merged {
+				parts = append(parts, d.QuoteIdent(col.name))
+			} else {
+				parts = append(parts, fmt.Sprintf("cast(%s as %s) as %s", d.QuoteIdent(col.name), merged, d.QuoteIdent(col.name)))
+			}
+		} else {
+			parts = append(parts, fmt.Sprintf("cast(null as %s) as %s", merged, d.QuoteIdent(col.name)))
+		}
+	}
+	if includeSourceFile {
+		parts = append(parts, fmt.Sprintf("'%s' as %s", escapeSQLString(src.filename), d.QuoteIdent("source_file")))
+	}
+	return fmt.Sprintf("select %s from %s", strings.Join(parts, ", "), d.QuoteIdent(src.tablename))
+}
+
+func escapeSQLString(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
+
+// mergeColumnNames returns columns' names, plus source_file if requested,
+// for the column list a Merge in "insert" mode needs.
+func mergeColumnNames(columns []mergeColumn, includeSourceFile bool) []string {
+	names := make([]string, 0, len(columns)+1)
+	for _, col := range columns {
+		names = append(names, col.name)
+	}
+	if includeSourceFile {
+		names = append(names, "source_file")
+	}
+	return names
+}
@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestWidenKind(t *testing.T) {
+	cases := []struct {
+		a, b, want fieldKind
+	}{
+		{kindInt, kindInt, kindInt},
+		{kindInt, kindFloat, kindFloat},
+		{kindFloat, kindInt, kindFloat},
+		{kindDate, kindBool, kindText},
+		{kindText, kindInt, kindText},
+		{kindUUID, kindUUID, kindUUID},
+	}
+	for _, c := range cases {
+		if got := widenKind(c.a, c.b); got != c.want {
+			t.Errorf("widenKind(%v, %v) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func intType() *fieldType {
+	ft := newFieldType()
+	ft.Check("1")
+	return ft
+}
+
+func floatType() *fieldType {
+	ft := newFieldType()
+	ft.Check("1.5")
+	return ft
+}
+
+func TestReconcileMergeSchemaWidensAndTracksNullability(t *testing.T) {
+	sources := []mergeSource{
+		{
+			filename:    "a.csv",
+			tablename:   "a",
+			columnNames: []string{"id", "amount"},
+			types:       []*fieldType{intType(), intType()},
+		},
+		{
+			filename:    "b.csv",
+			tablename:   "b",
+			columnNames: []string{"id", "amount", "extra"},
+			types:       []*fieldType{intType(), floatType(), intType()},
+		},
+	}
+	columns := reconcileMergeSchema(sources)
+	if len(columns) != 3 {
+		t.Fatalf("got %d columns, want 3: %+v", len(columns), columns)
+	}
+
+	byName := map[string]mergeColumn{}
+	for _, c := range columns {
+		byName[c.name] = c
+	}
+
+	if got := byName["amount"].kind; got != kindFloat {
+		t.Errorf("amount column kind = %v, want kindFloat (int widened with a source's float)", got)
+	}
+	if byName["extra"].notNull {
+		t.Errorf("extra column should not be NOT NULL: source a.csv doesn't have it")
+	}
+}
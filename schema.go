@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// columnHint is one column's entry in a --schema hints file. Anything left
+// zero-valued falls through to whatever inferSchema worked out on its own.
+type columnHint struct {
+	Name       string `yaml:"name,omitempty" json:"name,omitempty"`
+	Type       string `yaml:"type,omitempty" json:"type,omitempty"`
+	NotNull    *bool  `yaml:"not_null,omitempty" json:"not_null,omitempty"`
+	PrimaryKey bool   `yaml:"primary_key,omitempty" json:"primary_key,omitempty"`
+	Unique     bool   `yaml:"unique,omitempty" json:"unique,omitempty"`
+	Index      bool   `yaml:"index,omitempty" json:"index,omitempty"`
+	Skip       bool   `yaml:"skip,omitempty" json:"skip,omitempty"`
+}
+
+// schemaHintsFile is a --schema file's top-level shape: one columnHint per
+// inferred column, keyed by the same name it's given in the generated
+// CREATE TABLE.
+type schemaHintsFile struct {
+	Columns map[string]*columnHint `yaml:"columns" json:"columns"`
+}
+
+// loadSchemaHints reads a --schema file: YAML, unless path ends in .json,
+// in which case the same shape is read as JSON instead.
+func loadSchemaHints(path string) (*schemaHintsFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	hints := &schemaHintsFile{}
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		err = json.Unmarshal(data, hints)
+	} else {
+		err = yaml.Unmarshal(data, hints)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing --schema %s: %w", path, err)
+	}
+	if hints.Columns == nil {
+		hints.Columns = map[string]*columnHint{}
+	}
+	return hints, nil
+}
+
+// applySchemaHints applies hints on top of the columns inferSchema already
+// worked out: a hint can rename a column, force its SQL type or
+// nullability, ask for a PRIMARY KEY/UNIQUE/plain index, or drop the
+// column from the output table entirely. It must run after Check, so
+// hints win over inference.
+func applySchemaHints(hints *schemaHintsFile, rawNames, columnNames []string, types []*fieldType) (newRawNames, newColumnNames []string, newTypes []*fieldType) {
+	for i, name := range columnNames {
+		hint := hints.Columns[name]
+		if hint != nil && hint.Skip {
+			continue
+		}
+		ft := types[i]
+		if hint != nil {
+			if hint.Type != "" {
+				ft.Override = hint.Type
+			}
+			if hint.NotNull != nil {
+				ft.OverrideNotNull = hint.NotNull
+			}
+			ft.PrimaryKey = hint.PrimaryKey
+			ft.Unique = hint.Unique
+			ft.Index = hint.Index
+			if hint.Name != "" {
+				name = hint.Name
+			}
+		}
+		newRawNames = append(newRawNames, rawNames[i])
+		newColumnNames = append(newColumnNames, name)
+		newTypes = append(newTypes, ft)
+	}
+	return newRawNames, newColumnNames, newTypes
+}
+
+// emitSchemaHints writes columnNames/types back out as an editable
+// --schema hints file, so a messy CSV's inferred schema becomes a starting
+// point to hand-correct and re-feed rather than generated SQL to edit by
+// hand.
+func emitSchemaHints(path string, columnNames []string, types []*fieldType) error {
+	hints := &schemaHintsFile{Columns: map[string]*columnHint{}}
+	for i, name := range columnNames {
+		notNull := types[i].NotNull()
+		hints.Columns[name] = &columnHint{
+			Type:    strings.TrimSuffix(dialect.MapType(types[i]), " not null"),
+			NotNull: &notNull,
+		}
+	}
+
+	var data []byte
+	var err error
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		data, err = json.MarshalIndent(hints, "", "  ")
+	} else {
+		data, err = yaml.Marshal(hints)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// emitSchemaFor infers filename's schema and writes it to a hints file
+// alongside it: basename.schema.json if --schema named a .json file,
+// basename.schema.yaml otherwise.
+func emitSchemaFor(filename string) error {
+	_, columnNames, types, err := inferSchema(filename)
+	if err != nil {
+		return err
+	}
+	if schemaHints != nil {
+		_, columnNames, types = applySchemaHints(schemaHints, columnNames, columnNames, types)
+	}
+
+	ext := ".yaml"
+	if strings.HasSuffix(strings.ToLower(schemaPath), ".json") {
+		ext = ".json"
+	}
+	out := strings.TrimSuffix(filename, ".csv") + ".schema" + ext
+	if err := emitSchemaHints(out, columnNames, types); err != nil {
+		return err
+	}
+	log.Printf("%s: wrote inferred schema to %s", filename, out)
+	return nil
+}